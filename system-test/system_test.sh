@@ -7,6 +7,7 @@ trap "echo '** AT LEAST ONE OF TESTS FAILED **'" ERR
 set -eox pipefail
 
 . $(dirname $0)/../tools/retry.sh
+. $(dirname $0)/../tools/supported_node_versions.sh
 
 cd $(dirname $0)
 
@@ -14,11 +15,29 @@ if [[ -z "$BINARY_HOST" ]]; then
   ADDITIONAL_PACKAGES="python3 g++ make"
 fi
 
+# Additional environment variables to set in every test container, given as
+# NAME=VALUE lines in TEST_ENV. Values may contain spaces, for example
+# TEST_ENV=$'MIN_BUSYLOOP_PERCENT=20\nNODE_FLAGS=--trace-gc --no-opt'.
+TEST_ENV_ARGS=()
+while IFS= read -r e; do
+  if [[ -n "$e" ]]; then
+    TEST_ENV_ARGS+=(-e "$e")
+  fi
+done <<< "$TEST_ENV"
+
+# Host which serves no prebuilt binaries, used to exercise the
+# fallback-to-build install path. It is a fresh path in the e2e artifacts
+# bucket which nothing ever writes to, so every download from it fails.
+MISSING_BINARY_HOST="https://storage.googleapis.com/cprof-e2e-nodejs-artifacts/pprof-nodejs/no-binaries/$(date +%s)-$RANDOM"
+
 if [[ "$RUN_ONLY_V8_CANARY_TEST" == "true" ]]; then
   NVM_NODEJS_ORG_MIRROR="https://nodejs.org/download/v8-canary"
   NODE_VERSIONS=(node)
+elif [[ "$RUN_ONLY_NODE_NIGHTLY_TEST" == "true" ]]; then
+  NVM_NODEJS_ORG_MIRROR="https://nodejs.org/download/nightly"
+  NODE_VERSIONS=(node)
 else
-  NODE_VERSIONS=(10 12 14 15 16)
+  NODE_VERSIONS=(${SUPPORTED_NODE_VERSIONS[@]})
 fi
 
 for i in ${NODE_VERSIONS[@]}; do
@@ -28,17 +47,55 @@ for i in ${NODE_VERSIONS[@]}; do
       --build-arg  NVM_NODEJS_ORG_MIRROR="$NVM_NODEJS_ORG_MIRROR" \
       -t node$i-linux .
 
-  docker run  -v $PWD/..:/src -e BINARY_HOST="$BINARY_HOST" node$i-linux \
-      /src/system-test/test.sh
+  docker run  -v "$PWD/..":/src -e BENCH_DURATION_SEC=10 \
+      "${TEST_ENV_ARGS[@]}" -e BINARY_HOST="$BINARY_HOST" \
+      node$i-linux /src/system-test/test.sh
 
   # Test support for accurate line numbers with node versions supporting this
   # feature.
   if [ "$i" != "10" ]; then
-    docker run  -v $PWD/..:/src -e BINARY_HOST="$BINARY_HOST" \
+    docker run  -v "$PWD/..":/src -e BENCH_DURATION_SEC=10 \
+        "${TEST_ENV_ARGS[@]}" -e BINARY_HOST="$BINARY_HOST" \
         -e VERIFY_TIME_LINE_NUMBERS="true" node$i-linux \
         /src/system-test/test.sh
   fi
 
+  # Test that profiles are still valid when node runs with non-default V8
+  # flags. V8 flags may be renamed in canary builds, so skip these there.
+  if [[ -z "$NVM_NODEJS_ORG_MIRROR" ]]; then
+    docker run -v "$PWD/..":/src -e BENCH_DURATION_SEC=10 \
+        "${TEST_ENV_ARGS[@]}" -e BINARY_HOST="$BINARY_HOST" \
+        -e NODE_FLAGS="--no-opt --max-old-space-size=512" node$i-linux \
+        /src/system-test/test.sh
+
+    # --jitless is only available in node 12 and later.
+    if [ "$i" != "10" ]; then
+      docker run -v "$PWD/..":/src -e BENCH_DURATION_SEC=10 \
+          "${TEST_ENV_ARGS[@]}" -e BINARY_HOST="$BINARY_HOST" \
+          -e NODE_FLAGS="--jitless" node$i-linux \
+          /src/system-test/test.sh
+    fi
+  fi
+
+  # Test that the profiler works with frozen intrinsics. --frozen-intrinsics
+  # is only available in node 12 and later.
+  if [ "$i" != "10" ]; then
+    docker run -v "$PWD/..":/src -e BENCH_DURATION_SEC=10 \
+        "${TEST_ENV_ARGS[@]}" -e BINARY_HOST="$BINARY_HOST" \
+        -e NODE_FLAGS="--frozen-intrinsics" node$i-linux \
+        /src/system-test/test.sh
+  fi
+
+  # Test that installing falls back to building from source when prebuilt
+  # binaries cannot be downloaded. This requires a compiler in the image, so
+  # only run when ADDITIONAL_PACKAGES is set.
+  if [[ ! -z "$ADDITIONAL_PACKAGES" ]]; then
+    docker run -v "$PWD/..":/src -e BENCH_DURATION_SEC=5 \
+        "${TEST_ENV_ARGS[@]}" -e BINARY_HOST="$MISSING_BINARY_HOST" \
+        -e EXPECT_FALLBACK_TO_BUILD="true" node$i-linux \
+        /src/system-test/test.sh
+  fi
+
   # Skip running on alpine if NVM_NODEJS_ORG_MIRROR is specified.
   if [[ ! -z "$NVM_NODEJS_ORG_MIRROR" ]]; then
     continue
@@ -48,8 +105,9 @@ for i in ${NODE_VERSIONS[@]}; do
   retry docker build -f Dockerfile.node$i-alpine \
       --build-arg ADDITIONAL_PACKAGES="$ADDITIONAL_PACKAGES" -t node$i-alpine .
 
-  docker run -v $PWD/..:/src -e BINARY_HOST="$BINARY_HOST" node$i-alpine \
-      /src/system-test/test.sh
+  docker run -v "$PWD/..":/src -e BENCH_DURATION_SEC=10 \
+      "${TEST_ENV_ARGS[@]}" -e BINARY_HOST="$BINARY_HOST" \
+      node$i-alpine /src/system-test/test.sh
 done
 
 echo '** ALL TESTS PASSED **'
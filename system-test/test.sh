@@ -1,7 +1,18 @@
 #!/bin/bash
 
+# Prints the contents of any profiles in the current directory, so a failed
+# check can be diagnosed from the test log alone.
+dump_profiles() {
+  for profile in time.pb.gz heap.pb.gz; do
+    if [[ -f "$profile" ]]; then
+      pprof -top "$profile" || true
+      pprof -traces "$profile" || true
+    fi
+  done
+}
+
 trap "cd $(dirname $0)/.. && npm run clean" EXIT
-trap "echo '** TEST FAILED **'" ERR
+trap "dump_profiles; echo '** TEST FAILED **'" ERR
 
 . $(dirname $0)/../tools/retry.sh
 
@@ -18,9 +29,63 @@ npm_install() {
   timeout_after 60 npm install --quiet "${@}"
 }
 
+# Installs the packed profiler into the current directory, from prebuilt
+# binaries on BINARY_HOST if set and from source otherwise.
+install_profiler() {
+  retry npm_install --nodedir="$NODEDIR" "${@}" \
+      $([ -z "$BINARY_HOST" ] && echo "--build-from-source=pprof" \
+          || echo "--pprof_binary_host_mirror=$BINARY_HOST") \
+      "$PROFILER" >/dev/null
+}
+
+# Prints the total sample count of the given profile, that is the sum of the
+# first sample value over all samples.
+count_samples() {
+  pprof -raw "$1" | \
+      awk '/^Samples:/ { getline; s=1; next } /^Locations/ { s=0 } s { n+=$1 }
+           END { print n+0 }'
+}
+
+# Fails, with a message saying which, if the given profile is missing, cannot
+# be parsed, or contains no samples.
+check_profile() {
+  if [[ ! -f "$1" ]]; then
+    echo "Profile $1 is missing: the benchmark did not write it."
+    return 1
+  fi
+  if ! pprof -raw "$1" >/dev/null; then
+    echo "Profile $1 cannot be parsed: check the encoder output."
+    return 1
+  fi
+  if [[ $(count_samples "$1") -eq 0 ]]; then
+    echo "Profile $1 has no samples: check that the profiler was started."
+    return 1
+  fi
+}
+
+# Fails unless the given time profile has at least MIN_TIME_SAMPLES samples and
+# busyLoop accounts for at least MIN_BUSYLOOP_PERCENT percent of them.
+check_busyloop_share() {
+  local samples=$(count_samples "$1")
+  echo "samples in $1: $samples"
+  if [[ "$samples" -lt "$MIN_TIME_SAMPLES" ]]; then
+    echo "Profile $1 has $samples samples, expected at least $MIN_TIME_SAMPLES."
+    return 1
+  fi
+  local percent=$(pprof -filefunctions -top "$1" | \
+      awk '/busyLoop/ { sub("%", "", $2); print $2; exit }')
+  echo "busyLoop flat% in $1: ${percent:-0}"
+  awk -v p="${percent:-0}" -v min="$MIN_BUSYLOOP_PERCENT" \
+      'BEGIN { exit !(p >= min) }'
+}
+
 set -eox pipefail
 cd $(dirname $0)/..
 
+MIN_BUSYLOOP_PERCENT=${MIN_BUSYLOOP_PERCENT:-10}
+MIN_TIME_SAMPLES=${MIN_TIME_SAMPLES:-100}
+BENCH_DURATION_SEC=${BENCH_DURATION_SEC:-10}
+
 NODEDIR=$(dirname $(dirname $(which node)))
 
 # TODO: Remove when a new version of nan (current version 2.12.1) is released.
@@ -51,17 +116,45 @@ cp -r "$BENCHDIR" "$TESTDIR/busybench"
 cd "$TESTDIR/busybench"
 
 retry npm_install pify @types/pify typescript gts @types/node >/dev/null
-retry npm_install --nodedir="$NODEDIR" \
-    $([ -z "$BINARY_HOST" ] && echo "--build-from-source=pprof" \
-        || echo "--pprof_binary_host_mirror=$BINARY_HOST")\
-    "$PROFILER">/dev/null
+if [[ "$EXPECT_FALLBACK_TO_BUILD" == "true" ]]; then
+  # Keep the install script output, which npm 7 and later hide unless
+  # --foreground-scripts is set.
+  INSTALL_LOG="$TESTDIR/install.log"
+  install_profiler --foreground-scripts 2>"$INSTALL_LOG"
+  cat "$INSTALL_LOG"
+  # The prebuilt binary download must have failed before node-gyp started
+  # building the addon.
+  DOWNLOAD_FAILED_LINE=$(grep -n -m 1 -E \
+      "Pre-built binaries not (installable|found)" "$INSTALL_LOG" | cut -d: -f1)
+  BUILD_STARTED_LINE=$(grep -n -m 1 "^gyp info" "$INSTALL_LOG" | cut -d: -f1)
+  [[ -n "$DOWNLOAD_FAILED_LINE" ]]
+  [[ -n "$BUILD_STARTED_LINE" ]]
+  [[ "$DOWNLOAD_FAILED_LINE" -lt "$BUILD_STARTED_LINE" ]]
+  # node-gyp only writes config.gypi when building the addon from source.
+  test -f node_modules/pprof/build/config.gypi
+else
+  install_profiler
+fi
 
 if [[ "$VERIFY_TIME_LINE_NUMBERS" != "true" ]]; then
   npm run compile
 fi
 
 node -v
-node --trace-warnings "$BENCHPATH" 10 $VERIFY_TIME_LINE_NUMBERS
+# Record the ABI version and how the addon was installed, so runs against new
+# node builds show which ABIs have working prebuilt binaries.
+INSTALL_MODE=prebuilt
+if [[ -f node_modules/pprof/build/config.gypi ]]; then
+  INSTALL_MODE=source
+fi
+echo "ABI $(node -p process.versions.modules): $INSTALL_MODE"
+node --trace-warnings $NODE_FLAGS "$BENCHPATH" "$BENCH_DURATION_SEC" \
+    $VERIFY_TIME_LINE_NUMBERS
+
+check_profile time.pb.gz
+check_profile heap.pb.gz
+# busybench writes exactly one time and one heap profile.
+[[ $(ls *.pb.gz | wc -l) -eq 2 ]]
 
 if [[ "$VERIFY_TIME_LINE_NUMBERS" == "true" ]]; then
   pprof -lines -top -nodecount=2 time.pb.gz
@@ -78,6 +171,7 @@ else
   pprof -filefunctions -top -nodecount=2 heap.pb.gz | \
       grep "busyLoop.*src/busybench.ts"
 fi
+check_busyloop_share time.pb.gz
 
 
 echo '** TEST PASSED **'
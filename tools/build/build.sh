@@ -14,6 +14,8 @@
 # See the License for the specific language governing permissions and
 # limitations under the License.
 
+. $(dirname $0)/../supported_node_versions.sh
+
 # Fail on any error.
 set -e pipefail
 
@@ -28,10 +30,10 @@ mkdir -p "$ARTIFACTS_OUT"
 
 npm install --quiet
 
-for version in 10.0.0 12.0.0 14.0.0 15.0.0 16.0.0
+for version in ${SUPPORTED_NODE_VERSIONS[@]}
 do
   ./node_modules/.bin/node-pre-gyp configure rebuild package \
-      --target=$version --target_arch="x64"
+      --target=$version.0.0 --target_arch="x64"
   cp -r build/stage/* "${ARTIFACTS_OUT}/"
   rm -rf build
 done
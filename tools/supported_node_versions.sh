@@ -0,0 +1,21 @@
+#!/bin/bash
+
+# Copyright 2020 Google LLC
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     https://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Major versions of node for which prebuilt binaries are published, and which
+# the system test covers on linux and alpine. When changing this list, also
+# update the node matrix in .github/workflows/ci.yaml and add or remove the
+# matching system-test/Dockerfile.node*-alpine files.
+SUPPORTED_NODE_VERSIONS=(10 12 14 15 16)
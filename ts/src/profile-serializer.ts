@@ -265,10 +265,12 @@ function createAllocationValueType(
  * (https://github.com/google/pprof/blob/master/proto/profile.proto)
  *
  * @param prof - profile to be converted.
+ * @param startTimeNanos - start time of profile, in nanoseconds (POSIX time).
  * @param intervalMicros - average time (microseconds) between samples.
  */
 export function serializeTimeProfile(
   prof: TimeProfile,
+  startTimeNanos: number,
   intervalMicros: number,
   sourceMapper?: SourceMapper
 ): perftools.profiles.IProfile {
@@ -291,7 +293,7 @@ export function serializeTimeProfile(
 
   const profile = {
     sampleType: [sampleValueType, timeValueType],
-    timeNanos: Date.now() * 1000 * 1000,
+    timeNanos: startTimeNanos,
     durationNanos: (prof.endTime - prof.startTime) * 1000,
     periodType: timeValueType,
     period: intervalMicros,
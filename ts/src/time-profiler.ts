@@ -70,6 +70,7 @@ export function start(
   }
 
   profiling = true;
+  const startTimeNanos = Date.now() * 1000 * 1000;
   const runName = name || `pprof-${Date.now()}-${Math.random()}`;
   setSamplingInterval(intervalMicros);
   // Node.js contains an undocumented API for reporting idle status to V8.
@@ -85,7 +86,12 @@ export function start(
     const result = stopProfiling(runName, lineNumbers);
     // eslint-disable-next-line @typescript-eslint/no-explicit-any
     (process as any)._stopProfilerIdleNotifier();
-    const profile = serializeTimeProfile(result, intervalMicros, sourceMapper);
+    const profile = serializeTimeProfile(
+      result,
+      startTimeNanos,
+      intervalMicros,
+      sourceMapper
+    );
     return profile;
   };
 }
@@ -0,0 +1,61 @@
+/**
+ * Copyright 2021 Google Inc. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+import {perftools} from '../../proto/profile';
+
+const assert = require('assert');
+
+/**
+ * Asserts structural invariants which every serialized profile should
+ * satisfy, independent of its content.
+ */
+export function assertWellFormed(profile: perftools.profiles.IProfile) {
+  const strings = profile.stringTable!;
+  assert.strictEqual(strings[0], '');
+  const mappingIds = (profile.mapping || []).map(m => Number(m.id));
+  mappingIds.forEach((id, idx) => {
+    assert.ok(id > 0, `mapping ${idx} has no id`);
+    assert.strictEqual(mappingIds.indexOf(id), idx, `mapping ${id} repeated`);
+  });
+  profile.location!.forEach((loc, idx) => {
+    assert.strictEqual(
+      Number(loc.id),
+      idx + 1,
+      'location ids are not sequential'
+    );
+    assert.strictEqual(loc.line!.length, 1, `location ${loc.id} lines`);
+    const fnId = Number(loc.line![0].functionId);
+    assert.ok(fnId > 0 && fnId <= profile.function!.length);
+    const mappingId = Number(loc.mappingId || 0);
+    assert.ok(
+      mappingId === 0 || mappingIds.indexOf(mappingId) > -1,
+      `location ${loc.id} has unknown mapping ${mappingId}`
+    );
+  });
+  profile.function!.forEach((fn, idx) => {
+    assert.strictEqual(
+      Number(fn.id),
+      idx + 1,
+      'function ids are not sequential'
+    );
+    assert.notStrictEqual(strings[Number(fn.name)], '', `function ${fn.id}`);
+  });
+  for (const sample of profile.sample!) {
+    for (const locId of sample.locationId!.map(Number)) {
+      assert.ok(locId > 0 && locId <= profile.location!.length);
+    }
+  }
+}
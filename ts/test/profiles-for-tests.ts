@@ -599,6 +599,40 @@ export const anonymousFunctionTimeProfile: perftools.profiles.IProfile =
     period: 1000,
   });
 
+const unicodeTimeLeaf = {
+  name: 'función_π',
+  scriptName: '/app/源代码/ファイル.js',
+  scriptId: 1,
+  lineNumber: 3,
+  columnNumber: 7,
+  hitCount: 2,
+  children: [],
+};
+
+const unicodeTimeNode = {
+  name: 'ƒ𝒪𝒪',
+  scriptName: '/app/源代码/ファイル.js',
+  scriptId: 1,
+  lineNumber: 1,
+  columnNumber: 1,
+  hitCount: 1,
+  children: [unicodeTimeLeaf],
+};
+
+export const v8UnicodeTimeProfile: TimeProfile = Object.freeze({
+  startTime: 0,
+  endTime: 10 * 1000 * 1000,
+  topDownRoot: {
+    name: '(root)',
+    scriptName: 'root',
+    scriptId: 0,
+    lineNumber: 0,
+    columnNumber: 0,
+    hitCount: 0,
+    children: [unicodeTimeNode],
+  },
+});
+
 const heapWithPathLeaf1 = {
   name: 'foo2',
   scriptName: 'foo.ts',
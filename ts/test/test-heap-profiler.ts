@@ -16,10 +16,12 @@
 
 import * as sinon from 'sinon';
 
+import {perftools} from '../../proto/profile';
 import * as heapProfiler from '../src/heap-profiler';
 import * as v8HeapProfiler from '../src/heap-profiler-bindings';
 import {AllocationProfileNode} from '../src/v8-types';
 
+import {assertWellFormed} from './assert-profile';
 import {
   heapProfileExcludePath,
   heapProfileIncludePath,
@@ -31,6 +33,53 @@ import {
 const copy = require('deep-copy');
 const assert = require('assert');
 
+/**
+ * @return bytes attributed to samples whose stack includes a function with
+ * the given name.
+ */
+function bytesAllocatedBy(
+  profile: perftools.profiles.IProfile,
+  name: string
+): number {
+  const functionIds = new Set<number>();
+  for (const fn of profile.function!) {
+    if (profile.stringTable![Number(fn.name)] === name) {
+      functionIds.add(Number(fn.id));
+    }
+  }
+  const locationIds = new Set<number>();
+  for (const loc of profile.location!) {
+    if (functionIds.has(Number(loc.line![0].functionId))) {
+      locationIds.add(Number(loc.id));
+    }
+  }
+  let bytes = 0;
+  for (const sample of profile.sample!) {
+    if (sample.locationId!.some(id => locationIds.has(Number(id)))) {
+      bytes += Number(sample.value![1]);
+    }
+  }
+  return bytes;
+}
+
+// Allocates about 8 MiB which stays reachable through the returned array.
+function allocateBeforeHeapProfilerStart(): number[][] {
+  const arrays: number[][] = [];
+  for (let i = 0; i < 1024; i++) {
+    arrays.push(new Array<number>(1024));
+  }
+  return arrays;
+}
+
+// Allocates about 8 MiB which stays reachable through the returned array.
+function allocateAfterHeapProfilerStart(): number[][] {
+  const arrays: number[][] = [];
+  for (let i = 0; i < 1024; i++) {
+    arrays.push(new Array<number>(1024));
+  }
+  return arrays;
+}
+
 describe('HeapProfiler', () => {
   let startStub: sinon.SinonStub<[number, number], void>;
   let stopStub: sinon.SinonStub<[], void>;
@@ -185,3 +234,26 @@ describe('HeapProfiler', () => {
     });
   });
 });
+
+describe('HeapProfiler (w/o stubs)', () => {
+  afterEach(() => {
+    heapProfiler.stop();
+  });
+
+  it('should only attribute allocations made after starting on a warm heap', () => {
+    const before = allocateBeforeHeapProfilerStart();
+    heapProfiler.start(64 * 1024, 64);
+    const after = allocateAfterHeapProfilerStart();
+    const profile = heapProfiler.profile();
+    assertWellFormed(profile);
+    assert.ok(before.length > 0 && after.length > 0);
+    assert.ok(
+      bytesAllocatedBy(profile, 'allocateAfterHeapProfilerStart') > 0,
+      'expected allocations made after start to be sampled'
+    );
+    assert.strictEqual(
+      bytesAllocatedBy(profile, 'allocateBeforeHeapProfilerStart'),
+      0
+    );
+  });
+});
@@ -19,8 +19,14 @@ import {gunzip as gunzipPromise, gunzipSync} from 'zlib';
 
 import {perftools} from '../../proto/profile';
 import {encode, encodeSync} from '../src/profile-encoder';
+import {serializeTimeProfile} from '../src/profile-serializer';
 
-import {decodedTimeProfile, timeProfile} from './profiles-for-tests';
+import {
+  decodedTimeProfile,
+  timeProfile,
+  v8TimeProfile,
+  v8UnicodeTimeProfile,
+} from './profiles-for-tests';
 
 const assert = require('assert');
 const gunzip = pify(gunzipPromise);
@@ -42,4 +48,23 @@ describe('profile-encoded', () => {
       assert.deepEqual(decoded, decodedTimeProfile);
     });
   });
+  describe('determinism', () => {
+    it('should order the string table identically for identical input', () => {
+      const first = serializeTimeProfile(v8TimeProfile, 0, 1000);
+      const second = serializeTimeProfile(v8TimeProfile, 0, 1000);
+      assert.deepEqual(first.stringTable, second.stringTable);
+    });
+  });
+  describe('non-ASCII names', () => {
+    it('should preserve function and file names through encoding', async () => {
+      const profile = serializeTimeProfile(v8UnicodeTimeProfile, 0, 1000);
+      const encoded = await encode(profile);
+      const unzipped = await gunzip(encoded);
+      const decoded = perftools.profiles.Profile.decode(unzipped);
+      assert.deepEqual(decoded.stringTable, profile.stringTable);
+      for (const str of ['función_π', 'ƒ𝒪𝒪', '/app/源代码/ファイル.js']) {
+        assert.notStrictEqual(decoded.stringTable.indexOf(str), -1, str);
+      }
+    });
+  });
 });
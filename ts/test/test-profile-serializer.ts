@@ -22,6 +22,7 @@ import {
 } from '../src/profile-serializer';
 import {SourceMapper} from '../src/sourcemapper/sourcemapper';
 
+import {assertWellFormed} from './assert-profile';
 import {
   anonymousFunctionHeapProfile,
   anonymousFunctionTimeProfile,
@@ -52,12 +53,13 @@ describe('profile-serializer', () => {
 
   describe('serializeTimeProfile', () => {
     it('should produce expected profile', () => {
-      const timeProfileOut = serializeTimeProfile(v8TimeProfile, 1000);
+      const timeProfileOut = serializeTimeProfile(v8TimeProfile, 0, 1000);
       assert.deepEqual(timeProfileOut, timeProfile);
     });
     it('should produce expected profile when there is anyonmous function', () => {
       const timeProfileOut = serializeTimeProfile(
         v8AnonymousFunctionTimeProfile,
+        0,
         1000
       );
       assert.deepEqual(timeProfileOut, anonymousFunctionTimeProfile);
@@ -79,6 +81,21 @@ describe('profile-serializer', () => {
     });
   });
 
+  describe('structural invariants', () => {
+    it('should hold for time profiles', () => {
+      assertWellFormed(serializeTimeProfile(v8TimeProfile, 0, 1000));
+      assertWellFormed(
+        serializeTimeProfile(v8AnonymousFunctionTimeProfile, 0, 1000)
+      );
+    });
+    it('should hold for heap profiles', () => {
+      assertWellFormed(serializeHeapProfile(v8HeapProfile, 0, 512 * 1024));
+      assertWellFormed(
+        serializeHeapProfile(v8AnonymousFunctionHeapProfile, 0, 512 * 1024)
+      );
+    });
+  });
+
   describe('source map specified', () => {
     let sourceMapper: SourceMapper;
     before(async () => {
@@ -103,6 +120,7 @@ describe('profile-serializer', () => {
       it('should produce expected profile', () => {
         const timeProfileOut = serializeTimeProfile(
           v8TimeGeneratedProfile,
+          0,
           1000,
           sourceMapper
         );
@@ -15,22 +15,41 @@
  */
 
 import delay from 'delay';
+import {Session} from 'inspector';
+import * as pify from 'pify';
 import * as sinon from 'sinon';
+import {gunzip as gunzipPromise} from 'zlib';
+
+import {perftools} from '../../proto/profile';
+import * as heapProfiler from '../src/heap-profiler';
+import {encode} from '../src/profile-encoder';
 import * as time from '../src/time-profiler';
 import * as v8TimeProfiler from '../src/time-profiler-bindings';
+import {assertWellFormed} from './assert-profile';
 import {timeProfile, v8TimeProfile} from './profiles-for-tests';
 
 const assert = require('assert');
+const gunzip = pify(gunzipPromise);
 
 const PROFILE_OPTIONS = {
   durationMillis: 500,
   intervalMicros: 1000,
 };
 
+// eslint-disable-next-line @typescript-eslint/no-explicit-any
+function post(session: Session, method: string): Promise<any> {
+  return new Promise((resolve, reject) => {
+    session.post(method, (err, result) =>
+      err ? reject(err) : resolve(result)
+    );
+  });
+}
+
 describe('Time Profiler', () => {
   describe('profile', () => {
     it('should detect program or idle time', async () => {
       const profile = await time.profile(PROFILE_OPTIONS);
+      assertWellFormed(profile);
       assert.ok(profile.stringTable);
       assert.notDeepEqual(
         [
@@ -40,6 +59,122 @@ describe('Time Profiler', () => {
         [-1, -1]
       );
     });
+
+    it('should report the profile start time and duration', async () => {
+      const startNanos = Date.now() * 1000 * 1000;
+      const profile = await time.profile(PROFILE_OPTIONS);
+      const endNanos = Date.now() * 1000 * 1000;
+      const timeNanos = Number(profile.timeNanos);
+      const durationNanos = Number(profile.durationNanos);
+      assert.ok(durationNanos > 0);
+      // timeNanos is when collection started, so it must be much closer to
+      // startNanos than to the end of the profile.
+      assert.ok(timeNanos >= startNanos);
+      assert.ok(timeNanos - startNanos < durationNanos / 2);
+      // V8 measures duration with a different clock than Date.now(), so allow
+      // a millisecond of skew.
+      assert.ok(durationNanos <= endNanos - startNanos + 1000 * 1000);
+    });
+
+    it('should collect time and heap profiles back to back', async () => {
+      // Date.now() has millisecond resolution, so windows may appear to
+      // overlap by up to a millisecond.
+      const skewNanos = 1000 * 1000;
+      const maxGapNanos = 100 * 1000 * 1000;
+      let prevEndNanos: number | undefined;
+      heapProfiler.start(512 * 1024, 64);
+      try {
+        for (let i = 0; i < 5; i++) {
+          const stop = time.start(1000);
+          const heapProf = heapProfiler.profile();
+          await delay(100);
+          const timeProf = stop();
+          for (const profile of [timeProf, heapProf]) {
+            const decoded = perftools.profiles.Profile.decode(
+              await gunzip(await encode(profile))
+            );
+            assertWellFormed(profile);
+            assertWellFormed(decoded);
+            assert.deepEqual(decoded.stringTable, profile.stringTable);
+            assert.strictEqual(decoded.sample.length, profile.sample!.length);
+            for (const sample of decoded.sample) {
+              assert.ok(sample.locationId.length > 0);
+            }
+          }
+          assert.ok(timeProf.sample!.length > 0, `profile ${i} has no samples`);
+          const timeNanos = Number(timeProf.timeNanos);
+          if (prevEndNanos !== undefined) {
+            assert.ok(
+              timeNanos >= prevEndNanos - skewNanos,
+              `profile ${i} overlaps the previous profile`
+            );
+            assert.ok(
+              timeNanos - prevEndNanos < maxGapNanos,
+              `profile ${i} starts too long after the previous profile`
+            );
+          }
+          prevEndNanos = timeNanos + Number(timeProf.durationNanos);
+        }
+      } finally {
+        heapProfiler.stop();
+      }
+    });
+
+    it('should not carry idle time between profiles', async () => {
+      const intervalMicros = 1000;
+      for (let i = 0; i < 3; i++) {
+        if (i > 0) {
+          await delay(300);
+        }
+        const profile = await time.profile({
+          durationMillis: 100,
+          intervalMicros,
+        });
+        assert.ok(profile.sample!.length > 0, `profile ${i} has no samples`);
+        const durationNanos = Number(profile.durationNanos);
+        assert.ok(durationNanos < 300 * 1000 * 1000);
+        let sampleCount = 0;
+        for (const sample of profile.sample!) {
+          sampleCount += Number(sample.value![0]);
+        }
+        // Samples taken during the idle period before the profile would push
+        // the count past the number of intervals the profile covers.
+        const maxSamples = durationNanos / (intervalMicros * 1000) + 10;
+        assert.ok(
+          sampleCount <= maxSamples,
+          `profile ${i} has ${sampleCount} samples, expected <= ${maxSamples}`
+        );
+      }
+    });
+
+    it('should profile while the inspector CPU profiler is running', async () => {
+      const session = new Session();
+      session.connect();
+      try {
+        await post(session, 'Debugger.enable');
+        await post(session, 'Profiler.enable');
+        await post(session, 'Profiler.start');
+        const profile = await time.profile(PROFILE_OPTIONS);
+        const {profile: inspectorProfile} = await post(
+          session,
+          'Profiler.stop'
+        );
+        assert.ok(profile.sample!.length > 0);
+        assert.ok(inspectorProfile.nodes.length > 0);
+        assert.ok(inspectorProfile.endTime > inspectorProfile.startTime);
+      } finally {
+        session.disconnect();
+      }
+    });
+
+    it('should reject overlapping profiles', () => {
+      const stop = time.start(1000);
+      try {
+        assert.throws(() => time.start(1000), /already profiling/);
+      } finally {
+        stop();
+      }
+    });
   });
 
   describe('profile (w/ stubs)', () => {